@@ -3,9 +3,10 @@ package favicon
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"image"
-	"net/http"
 	"strings"
+	"time"
 
 	"github.com/disintegration/imaging"
 	"github.com/gin-gonic/gin"
@@ -28,140 +29,301 @@ type Options struct {
 	//
 	// Defaults to `#ffffff`.
 	ThemeColor string
-	// BackgroundColor is the background color as used in the webmanifest.
+	// BackgroundColor is the background color as used in the webmanifest,
+	// and as the canvas color behind the generated maskable icons.
 	//
 	// Defaults to `#ffffff`.
 	BackgroundColor string
+	// Description is the description as used in the webmanifest.
+	Description string
+	// Orientation is the orientation as used in the webmanifest.
+	Orientation string
+	// Scope is the scope as used in the webmanifest.
+	Scope string
+	// Categories is the categories as used in the webmanifest.
+	Categories []string
+	// Lang is the lang as used in the webmanifest.
+	Lang string
+	// Dir is the dir as used in the webmanifest.
+	Dir string
+	// Shortcuts is the shortcuts array as used in the webmanifest.
+	Shortcuts []ManifestShortcut
+	// Screenshots is the screenshots array as used in the webmanifest.
+	Screenshots []ManifestScreenshot
 
 	// TileColor is the tile color as used in the browserconfig.
 	//
 	// Defaults to `#da532c`.
 	TileColor string
 
-	// Favicon is png-encoded base icon.
+	// Favicon is the base icon, auto-detected as PNG (or any other format
+	// [github.com/disintegration/imaging] can decode), SVG, or ICO by
+	// sniffing its magic bytes.
+	//
+	// For control over which source is used for which output size, e.g.
+	// to avoid upscaling a small raster, use [Options.FaviconSources]
+	// instead.
 	Favicon []byte
+	// FaviconSources are the sources the favicon is generated from, tried
+	// in order; see [IconSource]. Favicon is always appended as a final,
+	// full-range fallback source.
+	FaviconSources []IconSource
 	// AppleTouchIcon is an alternative icon used as the apple-touch-icon.
 	//
-	// If not set, [Options.Favicon] is used.
+	// If not set, the best of Favicon/FaviconSources for 180x180 is used.
 	AppleTouchIcon []byte
+
+	// FaviconSVG is an optional vector version of Favicon.
+	//
+	// If set, it is served as /favicon.svg and advertised via a
+	// <link rel="icon" type="image/svg+xml"> tag.
+	FaviconSVG []byte
+
+	// MaxAge is the Cache-Control max-age advertised for all generated
+	// assets.
+	//
+	// Defaults to 7 days.
+	MaxAge time.Duration
+	// DisableCompression disables precomputing gzip and ExtraEncodings
+	// variants of generated assets.
+	DisableCompression bool
+	// ExtraEncodings are additional precomputed Content-Encoding variants
+	// offered alongside gzip, e.g. brotli via
+	// github.com/andybalholm/brotli. They are preferred over gzip when a
+	// request's Accept-Encoding allows it.
+	ExtraEncodings []Encoder
+
+	// Resolver, if set, computes a per-request [IconSet] instead of using
+	// the static [Options.Favicon]/[Options.AppleTouchIcon] baked in at
+	// startup. This allows multi-tenant apps to serve a different
+	// favicon/manifest per host, path, or authenticated tenant.
+	//
+	// [Options.ResolverKey] must be set too. The static, precomputed path
+	// remains the default when Resolver is nil.
+	Resolver Resolver
+	// ResolverKey returns the cache key under which the [IconSet]
+	// resolved by Resolver for a request is cached. Required if Resolver
+	// is set.
+	ResolverKey func(gctx *gin.Context) string
+	// ResolverCacheSize is the number of generated assets kept in the LRU
+	// cache backing Resolver.
+	//
+	// Defaults to 128.
+	ResolverCacheSize int
+
+	// Only whitelists which of the assets [Middleware] intercepts, by
+	// name (e.g. "favicon.ico", "apple-touch-icon.png"). Unused by [Add].
+	//
+	// If empty, Middleware intercepts all the assets it supports.
+	Only []string
 }
 
 // Add generates the below favicon formats and adds them to the passed
 // [gin.RouterGroup].
+//
+// If [Options.Resolver] is set, assets are instead resolved and generated
+// per request; see addDynamic.
 func Add(r *gin.RouterGroup, o Options) error {
-	faviconImg, err := imaging.Decode(bytes.NewReader(o.Favicon))
-	if err != nil {
-		return err
+	if o.Resolver != nil {
+		return addDynamic(r, o)
 	}
 
-	appleTouchIconImg := faviconImg
+	faviconRenderer := newIconRenderer(o.FaviconSources, o.Favicon)
+
+	appleTouchIconRenderer := faviconRenderer
 	if o.AppleTouchIcon != nil {
-		appleTouchIconImg, err = imaging.Decode(bytes.NewReader(o.AppleTouchIcon))
-		if err != nil {
-			return err
-		}
+		appleTouchIconRenderer = newIconRenderer(nil, o.AppleTouchIcon)
 	}
 
-	if err = addAppleTouchIcon(r, appleTouchIconImg); err != nil {
+	if err := addAppleTouchIcon(r, appleTouchIconRenderer, o); err != nil {
 		return err
 	}
 
-	if err = addFavicon(r, faviconImg); err != nil {
+	if err := addFavicon(r, faviconRenderer, o); err != nil {
 		return err
 	}
 
-	if err = addWebmanifest(r, faviconImg, o); err != nil {
+	if err := addFaviconICO(r, faviconRenderer, o); err != nil {
 		return err
 	}
 
-	return addBrowserConfig(r, faviconImg, o.TileColor)
+	if o.FaviconSVG != nil {
+		if err := addFaviconSVG(r, o.FaviconSVG, o); err != nil {
+			return err
+		}
+	}
+
+	if err := addWebmanifest(r, faviconRenderer, o); err != nil {
+		return err
+	}
+
+	return addBrowserConfig(r, faviconRenderer, o)
+}
+
+// faviconICOSizes are the sizes bundled into the generated favicon.ico.
+var faviconICOSizes = []int{16, 32, 48}
+
+// addFaviconICO registers a /favicon.ico handler serving a single ICO
+// container bundling renderer's output for each of faviconICOSizes.
+func addFaviconICO(r *gin.RouterGroup, renderer *iconRenderer, o Options) error {
+	data, err := encodeICO(renderer, faviconICOSizes)
+	if err != nil {
+		return err
+	}
+
+	return serveStatic(r, "favicon.ico", "image/x-icon", data, o)
+}
+
+// addFaviconSVG registers a /favicon.svg handler serving svg as-is.
+func addFaviconSVG(r *gin.RouterGroup, svg []byte, o Options) error {
+	return serveStatic(r, "favicon.svg", "image/svg+xml", svg, o)
 }
 
-func addAppleTouchIcon(r *gin.RouterGroup, img image.Image) error {
+func addAppleTouchIcon(r *gin.RouterGroup, renderer *iconRenderer, o Options) error {
 	return addIcon(r, addIconOptions{
-		name:   "apple-touch-icon.png",
-		img:    img,
-		size:   180,
-		format: imaging.PNG,
-		mime:   "image/png",
+		name:     "apple-touch-icon.png",
+		renderer: renderer,
+		size:     180,
+		o:        o,
 	})
 }
 
-func addFavicon(r *gin.RouterGroup, img image.Image) error {
+func addFavicon(r *gin.RouterGroup, renderer *iconRenderer, o Options) error {
 	err := addIcon(r, addIconOptions{
-		name:   "favicon.png",
-		img:    img,
-		size:   48,
-		format: imaging.PNG,
-		mime:   "image/png",
+		name:     "favicon.png",
+		renderer: renderer,
+		size:     48,
+		o:        o,
 	})
 	if err != nil {
 		return err
 	}
 
 	err = addIcon(r, addIconOptions{
-		name:   "favicon-32x32.png",
-		img:    img,
-		size:   32,
-		format: imaging.PNG,
-		mime:   "image/png",
+		name:     "favicon-32x32.png",
+		renderer: renderer,
+		size:     32,
+		o:        o,
 	})
 	if err != nil {
 		return err
 	}
 
 	return addIcon(r, addIconOptions{
-		name:   "favicon-16x16.png",
-		img:    img,
-		size:   16,
-		format: imaging.PNG,
-		mime:   "image/png",
+		name:     "favicon-16x16.png",
+		renderer: renderer,
+		size:     16,
+		o:        o,
 	})
 }
 
 type (
 	webmanifest struct {
-		Name            string            `json:"name"`
-		ShortName       string            `json:"short_name"`
-		Display         string            `json:"display"`
-		StartURL        string            `json:"start_url,omitempty"`
-		BackgroundColor string            `json:"background_color"`
-		ThemeColor      string            `json:"theme_color"`
-		Icons           []webmanifestIcon `json:"icons"`
+		Name            string               `json:"name"`
+		ShortName       string               `json:"short_name"`
+		Description     string               `json:"description,omitempty"`
+		Display         string               `json:"display"`
+		Orientation     string               `json:"orientation,omitempty"`
+		StartURL        string               `json:"start_url,omitempty"`
+		Scope           string               `json:"scope,omitempty"`
+		BackgroundColor string               `json:"background_color"`
+		ThemeColor      string               `json:"theme_color"`
+		Lang            string               `json:"lang,omitempty"`
+		Dir             string               `json:"dir,omitempty"`
+		Categories      []string             `json:"categories,omitempty"`
+		Icons           []webmanifestIcon    `json:"icons"`
+		Shortcuts       []ManifestShortcut   `json:"shortcuts,omitempty"`
+		Screenshots     []ManifestScreenshot `json:"screenshots,omitempty"`
 	}
 
 	webmanifestIcon struct {
-		Src   string `json:"src"`
-		Sizes string `json:"sizes"`
-		Type  string `json:"type"`
+		Src     string `json:"src"`
+		Sizes   string `json:"sizes"`
+		Type    string `json:"type"`
+		Purpose string `json:"purpose,omitempty"`
 	}
 )
 
-func addWebmanifest(r *gin.RouterGroup, img image.Image, o Options) error {
-	path := r.BasePath()
+// ManifestShortcut is an entry in the webmanifest's "shortcuts" array,
+// letting users jump to a specific part of the app from the OS's app
+// icon context menu.
+type ManifestShortcut struct {
+	Name      string `json:"name"`
+	ShortName string `json:"short_name,omitempty"`
+	URL       string `json:"url"`
+}
+
+// ManifestScreenshot is an entry in the webmanifest's "screenshots"
+// array, shown by some app stores/install prompts.
+type ManifestScreenshot struct {
+	Src        string `json:"src"`
+	Sizes      string `json:"sizes"`
+	Type       string `json:"type"`
+	FormFactor string `json:"form_factor,omitempty"`
+	Label      string `json:"label,omitempty"`
+}
+
+// androidChromeIconSizes are the "any" purpose icon sizes generated for
+// the webmanifest.
+var androidChromeIconSizes = []int{192, 512}
+
+// maskableIconSize is the size of the largest maskable purpose icon;
+// maskable icons are generated at each of androidChromeIconSizes.
+const maskableIconSize = 512
+
+// monochromeIconSize is the size the monochrome purpose icon is
+// generated at.
+const monochromeIconSize = 512
+
+// buildWebmanifestJSON renders the JSON body of site.webmanifest for the
+// assets registered under path.
+func buildWebmanifestJSON(path string, o Options) ([]byte, error) {
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
 	}
 
 	manifest := webmanifest{
-		Name:      o.Name,
-		ShortName: o.ShortName,
-		Display:   o.Display,
-		StartURL:  o.StartURL,
-		Icons: []webmanifestIcon{
-			{
-				Src:   path + "android-chrome-192x192.png",
-				Sizes: "192x192",
-				Type:  "image/png",
-			},
-			{
-				Src:   path + "/android-chrome-512x512.png",
-				Sizes: "512x512",
-				Type:  "image/png",
-			},
-		},
+		Name:            o.Name,
+		ShortName:       o.ShortName,
+		Description:     o.Description,
+		Display:         o.Display,
+		Orientation:     o.Orientation,
+		StartURL:        o.StartURL,
+		Scope:           o.Scope,
+		BackgroundColor: o.BackgroundColor,
+		ThemeColor:      o.ThemeColor,
+		Lang:            o.Lang,
+		Dir:             o.Dir,
+		Categories:      o.Categories,
+		Shortcuts:       o.Shortcuts,
+		Screenshots:     o.Screenshots,
+	}
+
+	for _, size := range androidChromeIconSizes {
+		manifest.Icons = append(manifest.Icons, webmanifestIcon{
+			Src:     fmt.Sprintf("%sandroid-chrome-%dx%d.png", path, size, size),
+			Sizes:   fmt.Sprintf("%dx%d", size, size),
+			Type:    "image/png",
+			Purpose: "any",
+		})
+	}
+
+	for _, size := range androidChromeIconSizes {
+		manifest.Icons = append(manifest.Icons, webmanifestIcon{
+			Src:     fmt.Sprintf("%sandroid-chrome-maskable-%dx%d.png", path, size, size),
+			Sizes:   fmt.Sprintf("%dx%d", size, size),
+			Type:    "image/png",
+			Purpose: "maskable",
+		})
 	}
 
+	manifest.Icons = append(manifest.Icons, webmanifestIcon{
+		Src:     fmt.Sprintf("%sandroid-chrome-monochrome-%dx%d.png", path, monochromeIconSize, monochromeIconSize),
+		Sizes:   fmt.Sprintf("%dx%d", monochromeIconSize, monochromeIconSize),
+		Type:    "image/png",
+		Purpose: "monochrome",
+	})
+
 	if manifest.Display == "" {
 		manifest.Display = "standalone"
 	}
@@ -174,41 +336,67 @@ func addWebmanifest(r *gin.RouterGroup, img image.Image, o Options) error {
 		manifest.BackgroundColor = "#ffffff"
 	}
 
-	manifestJSON, err := json.Marshal(manifest)
+	return json.Marshal(manifest)
+}
+
+func addWebmanifest(r *gin.RouterGroup, renderer *iconRenderer, o Options) error {
+	manifestJSON, err := buildWebmanifestJSON(r.BasePath(), o)
 	if err != nil {
 		return err
 	}
 
-	r.GET("/site.webmanifest", func(gctx *gin.Context) {
-		gctx.Data(http.StatusOK, "application/manifest+json", manifestJSON)
-	})
+	if err = serveStatic(r, "site.webmanifest", "application/manifest+json", manifestJSON, o); err != nil {
+		return err
+	}
 
-	err = addIcon(r, addIconOptions{
-		name:   "android-chrome-192x192.png",
-		img:    img,
-		size:   192,
-		format: imaging.PNG,
-		mime:   "image/png",
-	})
+	for _, size := range androidChromeIconSizes {
+		err = addIcon(r, addIconOptions{
+			name:     fmt.Sprintf("android-chrome-%dx%d.png", size, size),
+			renderer: renderer,
+			size:     size,
+			o:        o,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	bg := o.BackgroundColor
+	if bg == "" {
+		bg = "#ffffff"
+	}
+
+	bgColor := parseHexColor(bg)
+
+	for _, size := range androidChromeIconSizes {
+		fg, err := renderer.At(int(float64(size) * (1 - 2*maskableSafeZone)))
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("android-chrome-maskable-%dx%d.png", size, size)
+		if err = addPNGIcon(r, name, maskableIcon(fg, size, bgColor), o); err != nil {
+			return err
+		}
+	}
+
+	monoImg, err := renderer.At(monochromeIconSize)
 	if err != nil {
 		return err
 	}
 
-	return addIcon(r, addIconOptions{
-		name:   "android-chrome-512x512.png",
-		img:    img,
-		size:   512,
-		format: imaging.PNG,
-		mime:   "image/png",
-	})
+	name := fmt.Sprintf("android-chrome-monochrome-%dx%d.png", monochromeIconSize, monochromeIconSize)
+
+	return addPNGIcon(r, name, monochromeIcon(monoImg, monochromeIconSize), o)
 }
 
-func addBrowserConfig(r *gin.RouterGroup, img image.Image, tileColor string) error {
+// buildBrowserConfigXML renders the XML body of browserconfig.xml.
+func buildBrowserConfigXML(tileColor string) []byte {
 	if tileColor == "" {
 		tileColor = "#da532c"
 	}
 
-	browserConfig := []byte(`<?xml version="1.0" encoding="utf-8"?>
+	return []byte(`<?xml version="1.0" encoding="utf-8"?>
 <browserconfig>
     <msapplication>
         <tile>
@@ -217,42 +405,45 @@ func addBrowserConfig(r *gin.RouterGroup, img image.Image, tileColor string) err
         </tile>
     </msapplication>
 </browserconfig>`)
+}
 
-	r.GET("/browserconfig.xml", func(gctx *gin.Context) {
-		gctx.Data(http.StatusOK, "application/xml", browserConfig)
-	})
+func addBrowserConfig(r *gin.RouterGroup, renderer *iconRenderer, o Options) error {
+	browserConfig := buildBrowserConfigXML(o.TileColor)
+
+	if err := serveStatic(r, "browserconfig.xml", "application/xml", browserConfig, o); err != nil {
+		return err
+	}
 
 	return addIcon(r, addIconOptions{
-		name:   "mstile-150x150.png",
-		img:    img,
-		size:   150,
-		format: imaging.PNG,
-		mime:   "image/png",
+		name:     "mstile-150x150.png",
+		renderer: renderer,
+		size:     150,
+		o:        o,
 	})
 }
 
 type addIconOptions struct {
-	name   string
-	img    image.Image
-	size   int
-	format imaging.Format
-	mime   string
+	name     string
+	renderer *iconRenderer
+	size     int
+	o        Options
 }
 
 func addIcon(r *gin.RouterGroup, o addIconOptions) error {
-	ico := imaging.Resize(o.img, o.size, o.size, imaging.Lanczos)
-
-	var buf bytes.Buffer
-	err := imaging.Encode(&buf, ico, o.format)
+	img, err := o.renderer.At(o.size)
 	if err != nil {
 		return err
 	}
 
-	data := buf.Bytes()
+	return addPNGIcon(r, o.name, img, o.o)
+}
 
-	r.GET("/"+o.name, func(gctx *gin.Context) {
-		gctx.Data(http.StatusOK, o.mime, data)
-	})
+// addPNGIcon serves img, already at its final size, as a PNG at name.
+func addPNGIcon(r *gin.RouterGroup, name string, img image.Image, o Options) error {
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, imaging.PNG); err != nil {
+		return err
+	}
 
-	return nil
+	return serveStatic(r, name, "image/png", buf.Bytes(), o)
 }
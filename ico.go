@@ -0,0 +1,68 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/disintegration/imaging"
+)
+
+// icoHeaderSize is the size of the ICONDIR header.
+const icoHeaderSize = 6
+
+// icoEntrySize is the size of a single ICONDIRENTRY.
+const icoEntrySize = 16
+
+// encodeICO encodes a multi-resolution ICO containing one PNG-encoded
+// frame per size in sizes, rendered from renderer. Browsers accept
+// PNG-in-ICO, so no legacy BMP encoding is performed.
+func encodeICO(renderer *iconRenderer, sizes []int) ([]byte, error) {
+	pngs := make([][]byte, len(sizes))
+
+	for i, size := range sizes {
+		img, err := renderer.At(size)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, img, imaging.PNG); err != nil {
+			return nil, err
+		}
+
+		pngs[i] = buf.Bytes()
+	}
+
+	var out bytes.Buffer
+
+	// ICONDIR
+	_ = binary.Write(&out, binary.LittleEndian, uint16(0))          // reserved
+	_ = binary.Write(&out, binary.LittleEndian, uint16(1))          // type: icon
+	_ = binary.Write(&out, binary.LittleEndian, uint16(len(sizes))) // image count
+
+	offset := uint32(icoHeaderSize + len(sizes)*icoEntrySize)
+
+	for i, size := range sizes {
+		dim := byte(size)
+		if size >= 256 {
+			dim = 0 // 0 means 256px
+		}
+
+		out.WriteByte(dim)                                      // width
+		out.WriteByte(dim)                                      // height
+		out.WriteByte(0)                                        // color count
+		out.WriteByte(0)                                        // reserved
+		_ = binary.Write(&out, binary.LittleEndian, uint16(1))  // color planes
+		_ = binary.Write(&out, binary.LittleEndian, uint16(32)) // bits per pixel
+		_ = binary.Write(&out, binary.LittleEndian, uint32(len(pngs[i])))
+		_ = binary.Write(&out, binary.LittleEndian, offset)
+
+		offset += uint32(len(pngs[i]))
+	}
+
+	for _, png := range pngs {
+		out.Write(png)
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package favicon
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestEncodeICORoundTrip(t *testing.T) {
+	img := imaging.New(32, 32, color.NRGBA{R: 0xff, A: 0xff})
+	renderer := newIconRendererFromImage(img)
+
+	sizes := []int{16, 32}
+
+	data, err := encodeICO(renderer, sizes)
+	if err != nil {
+		t.Fatalf("encodeICO: %v", err)
+	}
+
+	for _, size := range sizes {
+		frame, err := bestICOFrame(data, size)
+		if err != nil {
+			t.Fatalf("bestICOFrame(%d): %v", size, err)
+		}
+
+		bounds := frame.Bounds()
+		if bounds.Dx() != size || bounds.Dy() != size {
+			t.Errorf("bestICOFrame(%d) size = %dx%d, want %dx%d", size, bounds.Dx(), bounds.Dy(), size, size)
+		}
+	}
+}
+
+func TestBestICOFramePicksSmallestCoveringFrame(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	renderer := newIconRendererFromImage(img)
+
+	data, err := encodeICO(renderer, []int{16, 32, 48})
+	if err != nil {
+		t.Fatalf("encodeICO: %v", err)
+	}
+
+	frame, err := bestICOFrame(data, 20)
+	if err != nil {
+		t.Fatalf("bestICOFrame: %v", err)
+	}
+
+	if bounds := frame.Bounds(); bounds.Dx() != 32 {
+		t.Errorf("bestICOFrame(20) picked a %dx%d frame, want 32x32 (smallest covering 20)", bounds.Dx(), bounds.Dy())
+	}
+
+	frame, err = bestICOFrame(data, 64)
+	if err != nil {
+		t.Fatalf("bestICOFrame: %v", err)
+	}
+
+	if bounds := frame.Bounds(); bounds.Dx() != 48 {
+		t.Errorf("bestICOFrame(64) picked a %dx%d frame, want 48x48 (largest available)", bounds.Dx(), bounds.Dy())
+	}
+}
+
+func TestBestICOFrameRejectsTruncatedInput(t *testing.T) {
+	oneEntryHeader := make([]byte, icoHeaderSize)
+	binary.LittleEndian.PutUint16(oneEntryHeader[4:6], 1)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"header only, zero frames", make([]byte, icoHeaderSize)},
+		{"truncated entry", append(oneEntryHeader, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := bestICOFrame(tt.data, 16); err == nil {
+				t.Error("bestICOFrame returned no error for truncated/malformed input")
+			}
+		})
+	}
+}
+
+func TestBestICOFrameRejectsOutOfBoundsOffset(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	renderer := newIconRendererFromImage(img)
+
+	data, err := encodeICO(renderer, []int{16})
+	if err != nil {
+		t.Fatalf("encodeICO: %v", err)
+	}
+
+	truncated := data[:len(data)-4]
+
+	if _, err := bestICOFrame(truncated, 16); err == nil {
+		t.Error("bestICOFrame returned no error for a frame whose length/offset runs past the buffer")
+	}
+}
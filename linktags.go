@@ -0,0 +1,57 @@
+package favicon
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+)
+
+// LinkTags renders the <link>/<meta> tags for the assets [Add] registers
+// under basePath, so callers don't have to hand-write them in their
+// templates. The returned tags match o exactly, e.g. a
+// <link rel="icon" type="image/svg+xml"> is only included if
+// [Options.FaviconSVG] is set.
+//
+// basePath and the relevant Options fields (currently [Options.TileColor]
+// and [Options.ThemeColor]) are HTML-escaped before being written out,
+// since, with a [Resolver], o can carry per-request/tenant values that
+// aren't necessarily trusted.
+func LinkTags(basePath string, o Options) template.HTML {
+	path := basePath
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	path = html.EscapeString(path)
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `<link rel="icon" type="image/x-icon" href="%sfavicon.ico">`+"\n", path)
+
+	if o.FaviconSVG != nil {
+		fmt.Fprintf(&b, `<link rel="icon" type="image/svg+xml" href="%sfavicon.svg">`+"\n", path)
+	}
+
+	fmt.Fprintf(&b, `<link rel="icon" type="image/png" sizes="32x32" href="%sfavicon-32x32.png">`+"\n", path)
+	fmt.Fprintf(&b, `<link rel="icon" type="image/png" sizes="16x16" href="%sfavicon-16x16.png">`+"\n", path)
+	fmt.Fprintf(&b, `<link rel="apple-touch-icon" sizes="180x180" href="%sapple-touch-icon.png">`+"\n", path)
+	fmt.Fprintf(&b, `<link rel="manifest" href="%ssite.webmanifest">`+"\n", path)
+	fmt.Fprintf(&b, `<meta name="msapplication-config" content="%sbrowserconfig.xml">`+"\n", path)
+
+	tileColor := o.TileColor
+	if tileColor == "" {
+		tileColor = "#da532c"
+	}
+
+	fmt.Fprintf(&b, `<meta name="msapplication-TileColor" content="%s">`+"\n", html.EscapeString(tileColor))
+
+	themeColor := o.ThemeColor
+	if themeColor == "" {
+		themeColor = "#ffffff"
+	}
+
+	fmt.Fprintf(&b, `<meta name="theme-color" content="%s">`, html.EscapeString(themeColor))
+
+	return template.HTML(b.String()) //nolint:gosec // every interpolated value above is passed through html.EscapeString.
+}
@@ -0,0 +1,47 @@
+package favicon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkTagsEscapesUntrustedOptions(t *testing.T) {
+	const payload = `"><script>alert(1)</script>`
+
+	got := string(LinkTags("/assets", Options{
+		TileColor:  payload,
+		ThemeColor: payload,
+	}))
+
+	if strings.Contains(got, "<script>") {
+		t.Fatalf("LinkTags did not escape an injected value, got:\n%s", got)
+	}
+
+	if !strings.Contains(got, "&lt;script&gt;") {
+		t.Errorf("LinkTags output missing the expected escaped payload, got:\n%s", got)
+	}
+}
+
+func TestLinkTagsEscapesBasePath(t *testing.T) {
+	const payload = `/assets"><script>alert(1)</script>`
+
+	got := string(LinkTags(payload, Options{}))
+
+	if strings.Contains(got, "<script>") {
+		t.Fatalf("LinkTags did not escape an injected basePath, got:\n%s", got)
+	}
+}
+
+func TestLinkTagsDefaultsAndFaviconSVG(t *testing.T) {
+	got := string(LinkTags("/assets", Options{}))
+
+	if strings.Contains(got, "favicon.svg") {
+		t.Error("LinkTags emitted a favicon.svg link with no FaviconSVG set")
+	}
+
+	got = string(LinkTags("/assets", Options{FaviconSVG: []byte("<svg/>")}))
+
+	if !strings.Contains(got, "/assets/favicon.svg") {
+		t.Error("LinkTags did not emit a favicon.svg link with FaviconSVG set")
+	}
+}
@@ -0,0 +1,70 @@
+package favicon
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// maskableSafeZone is the fraction of a maskable icon's canvas reserved
+// as padding around the foreground image on every side, per the
+// maskable icon spec: https://www.w3.org/TR/appmanifest/#maskable-icons.
+const maskableSafeZone = 0.2
+
+// maskableIcon composites img, scaled to fit the safe zone, centered on a
+// size x size canvas filled with bg.
+func maskableIcon(img image.Image, size int, bg color.Color) image.Image {
+	inner := int(float64(size) * (1 - 2*maskableSafeZone))
+
+	fg := imaging.Resize(img, inner, inner, imaging.Lanczos)
+	canvas := imaging.New(size, size, bg)
+
+	return imaging.PasteCenter(canvas, fg)
+}
+
+// monochromeIcon returns a size x size, alpha-only variant of img for the
+// "monochrome" manifest icon purpose: every pixel is recolored to black,
+// preserving img's alpha channel.
+func monochromeIcon(img image.Image, size int) image.Image {
+	resized := imaging.Resize(img, size, size, imaging.Lanczos)
+
+	bounds := resized.Bounds()
+	out := image.NewNRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			_, _, _, a := resized.At(x, y).RGBA()
+			out.SetNRGBA(x, y, color.NRGBA{A: uint8(a >> 8)})
+		}
+	}
+
+	return out
+}
+
+// parseHexColor parses a CSS-style "#rrggbb" or "#rgb" color, returning
+// opaque black if hex is empty or malformed.
+func parseHexColor(hex string) color.Color {
+	hex = strings.TrimPrefix(hex, "#")
+
+	var r, g, b uint8
+
+	switch len(hex) {
+	case 6:
+		if _, err := fmt.Sscanf(hex, "%02x%02x%02x", &r, &g, &b); err != nil {
+			return color.Black
+		}
+	case 3:
+		if _, err := fmt.Sscanf(hex, "%1x%1x%1x", &r, &g, &b); err != nil {
+			return color.Black
+		}
+
+		r, g, b = r*17, g*17, b*17
+	default:
+		return color.Black
+	}
+
+	return color.NRGBA{R: r, G: g, B: b, A: 0xff}
+}
@@ -0,0 +1,72 @@
+package favicon
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		want color.Color
+	}{
+		{"six digit", "#112233", color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}},
+		{"six digit no hash", "112233", color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}},
+		{"three digit", "#123", color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}},
+		{"empty", "", color.Black},
+		{"wrong length", "#1234", color.Black},
+		{"non-hex", "#gggggg", color.Black},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseHexColor(tt.hex); got != tt.want {
+				t.Errorf("parseHexColor(%q) = %#v, want %#v", tt.hex, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskableIconSizeAndBackground(t *testing.T) {
+	src := imaging.New(10, 10, color.NRGBA{R: 0xff, A: 0xff})
+
+	const size = 48
+
+	out := maskableIcon(src, size, color.NRGBA{B: 0xff, A: 0xff})
+
+	bounds := out.Bounds()
+	if bounds.Dx() != size || bounds.Dy() != size {
+		t.Fatalf("maskableIcon size = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), size, size)
+	}
+
+	corner := out.At(0, 0)
+	r, g, b, _ := corner.RGBA()
+	if r != 0 || g != 0 || b == 0 {
+		t.Errorf("corner pixel = %v, want background blue, got r=%d g=%d b=%d", corner, r, g, b)
+	}
+}
+
+func TestMonochromeIconPreservesAlphaOnly(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 0xff, G: 0x00, B: 0x00, A: 0x80})
+
+	out := monochromeIcon(src, 4)
+
+	bounds := out.Bounds()
+	if bounds.Dx() != 4 || bounds.Dy() != 4 {
+		t.Fatalf("monochromeIcon size = %dx%d, want 4x4", bounds.Dx(), bounds.Dy())
+	}
+
+	r, g, b, a := out.At(0, 0).RGBA()
+	if r != 0 || g != 0 || b != 0 {
+		t.Errorf("monochromeIcon pixel color = r=%d g=%d b=%d, want all zero", r, g, b)
+	}
+
+	if a == 0 {
+		t.Error("monochromeIcon dropped alpha entirely")
+	}
+}
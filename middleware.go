@@ -0,0 +1,127 @@
+package favicon
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/gin-gonic/gin"
+)
+
+// errMiddlewareNoResolver is returned by Middleware when Options.Resolver
+// is set, which it doesn't support.
+var errMiddlewareNoResolver = errors.New("favicon: Middleware does not support Options.Resolver")
+
+// middlewareIcons are the PNG icons Middleware can serve, besides
+// favicon.ico and (if Options.FaviconSVG is set) favicon.svg.
+var middlewareIcons = []struct {
+	name string
+	size int
+}{
+	{"favicon.png", 48},
+	{"favicon-32x32.png", 32},
+	{"favicon-16x16.png", 16},
+	{"apple-touch-icon.png", 180},
+}
+
+// Middleware returns a [gin.HandlerFunc] that serves the favicon assets
+// generated from o for any request path matching one of them (e.g.
+// /favicon.ico), aborting the chain so that no downstream handler runs.
+//
+// This covers two gaps [Add] has: a [gin.RouterGroup.GET] for
+// "/favicon.ico" only matches if nothing registered before it already
+// claimed the route, and it only matches under the router group's mount
+// path, whereas browsers always request /favicon.ico from the domain
+// root. Register Middleware on the [gin.Engine] itself, before other
+// routes/static file servers, to cover both.
+//
+// It shares its encoding and caching code paths with Add, and supports
+// [Options.Only] to whitelist which asset names it intercepts. It does
+// not support [Options.Resolver]; use Add's dynamic path for per-request
+// favicons instead.
+func Middleware(o Options) (gin.HandlerFunc, error) {
+	if o.Resolver != nil {
+		return nil, errMiddlewareNoResolver
+	}
+
+	handlers, err := buildMiddlewareHandlers(o)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.Only) > 0 {
+		allowed := make(map[string]bool, len(o.Only))
+		for _, name := range o.Only {
+			allowed[name] = true
+		}
+
+		for name := range handlers {
+			if !allowed[name] {
+				delete(handlers, name)
+			}
+		}
+	}
+
+	return func(gctx *gin.Context) {
+		name := strings.TrimPrefix(gctx.Request.URL.Path, "/")
+
+		handler, ok := handlers[name]
+		if !ok {
+			return
+		}
+
+		handler(gctx)
+		gctx.Abort()
+	}, nil
+}
+
+// buildMiddlewareHandlers generates every asset Middleware can serve and
+// builds their handlers, keyed by asset name.
+func buildMiddlewareHandlers(o Options) (map[string]gin.HandlerFunc, error) {
+	renderer := newIconRenderer(o.FaviconSources, o.Favicon)
+
+	handlers := make(map[string]gin.HandlerFunc, len(middlewareIcons)+2)
+
+	icoData, err := encodeICO(renderer, faviconICOSizes)
+	if err != nil {
+		return nil, err
+	}
+
+	icoHandler, err := buildStaticHandler("image/x-icon", icoData, o)
+	if err != nil {
+		return nil, err
+	}
+
+	handlers["favicon.ico"] = icoHandler
+
+	if o.FaviconSVG != nil {
+		svgHandler, err := buildStaticHandler("image/svg+xml", o.FaviconSVG, o)
+		if err != nil {
+			return nil, err
+		}
+
+		handlers["favicon.svg"] = svgHandler
+	}
+
+	for _, icon := range middlewareIcons {
+		img, err := renderer.At(icon.size)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err = imaging.Encode(&buf, img, imaging.PNG); err != nil {
+			return nil, err
+		}
+
+		handler, err := buildStaticHandler("image/png", buf.Bytes(), o)
+		if err != nil {
+			return nil, err
+		}
+
+		handlers[icon.name] = handler
+	}
+
+	return handlers, nil
+}
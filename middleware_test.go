@@ -0,0 +1,121 @@
+package favicon
+
+import (
+	"bytes"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/gin-gonic/gin"
+)
+
+func testFaviconPNG(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, imaging.New(32, 32, color.NRGBA{R: 0xff, A: 0xff}), imaging.PNG); err != nil {
+		t.Fatalf("encode test favicon: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestMiddlewareRejectsResolver(t *testing.T) {
+	_, err := Middleware(Options{
+		Resolver:    func(*gin.Context) (*IconSet, error) { return nil, nil },
+		ResolverKey: func(*gin.Context) string { return "" },
+	})
+	if err != errMiddlewareNoResolver {
+		t.Fatalf("Middleware with Options.Resolver set, err = %v, want errMiddlewareNoResolver", err)
+	}
+}
+
+func TestMiddlewareServesFaviconICOAndAborts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mw, err := Middleware(Options{Favicon: testFaviconPNG(t)})
+	if err != nil {
+		t.Fatalf("Middleware: %v", err)
+	}
+
+	downstreamCalled := false
+
+	engine := gin.New()
+	engine.Use(mw)
+	engine.NoRoute(func(gctx *gin.Context) {
+		downstreamCalled = true
+		gctx.Status(http.StatusNotFound)
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /favicon.ico status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "image/x-icon" {
+		t.Errorf("GET /favicon.ico Content-Type = %q, want image/x-icon", ct)
+	}
+
+	if downstreamCalled {
+		t.Error("Middleware did not abort the chain for /favicon.ico")
+	}
+}
+
+func TestMiddlewarePassesThroughUnmatchedPaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mw, err := Middleware(Options{Favicon: testFaviconPNG(t)})
+	if err != nil {
+		t.Fatalf("Middleware: %v", err)
+	}
+
+	downstreamCalled := false
+
+	engine := gin.New()
+	engine.Use(mw)
+	engine.NoRoute(func(gctx *gin.Context) {
+		downstreamCalled = true
+		gctx.Status(http.StatusNotFound)
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/some-other-page", nil))
+
+	if !downstreamCalled {
+		t.Error("Middleware intercepted a path it doesn't own")
+	}
+}
+
+func TestMiddlewareOnlyFiltersAssets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mw, err := Middleware(Options{
+		Favicon: testFaviconPNG(t),
+		Only:    []string{"favicon.ico"},
+	})
+	if err != nil {
+		t.Fatalf("Middleware: %v", err)
+	}
+
+	engine := gin.New()
+	engine.Use(mw)
+	engine.NoRoute(func(gctx *gin.Context) { gctx.Status(http.StatusNotFound) })
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /favicon.ico status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/favicon.png", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /favicon.png (excluded by Only) status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
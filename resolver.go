@@ -0,0 +1,277 @@
+package favicon
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	"github.com/gin-gonic/gin"
+)
+
+// errResolverKeyRequired is returned by Add when Options.Resolver is set
+// without Options.ResolverKey.
+var errResolverKeyRequired = errors.New("favicon: Options.ResolverKey must be set when Options.Resolver is set")
+
+// Resolver computes a per-request [IconSet], allowing multi-tenant apps to
+// serve a different favicon per host, path, or authenticated tenant,
+// instead of the single [Options.Favicon] baked in at startup.
+type Resolver func(gctx *gin.Context) (*IconSet, error)
+
+// IconSet holds the decoded images and resolved [Options] used to
+// generate one set of favicon assets, as returned by a [Resolver].
+type IconSet struct {
+	// Favicon is the decoded base icon.
+	Favicon image.Image
+	// AppleTouchIcon is an alternative icon used as the apple-touch-icon.
+	//
+	// If nil, Favicon is used.
+	AppleTouchIcon image.Image
+	// Options holds the non-image settings (name, colors, ...) used to
+	// generate the webmanifest and browserconfig.
+	Options Options
+}
+
+// defaultResolverCacheSize is used when [Options.ResolverCacheSize] is
+// zero.
+const defaultResolverCacheSize = 128
+
+// addDynamic registers handlers that call o.Resolver on every request and
+// serve the resolved [IconSet]'s assets, instead of the closures over a
+// single, precomputed image that [Add] installs by default.
+//
+// Generated bytes are cached in an LRU keyed by [Options.ResolverKey], so
+// repeat requests for the same tenant don't redo the resizing/encoding
+// work. ETag/Cache-Control/compression handling from [serveStatic] is
+// not used for the resolved assets, since they can differ on every
+// request; only the unresolved favicon.svg is served through it.
+func addDynamic(r *gin.RouterGroup, o Options) error {
+	if o.ResolverKey == nil {
+		return errResolverKeyRequired
+	}
+
+	cacheSize := o.ResolverCacheSize
+	if cacheSize == 0 {
+		cacheSize = defaultResolverCacheSize
+	}
+
+	cache := newAssetCache(cacheSize)
+
+	path := r.BasePath()
+
+	for _, a := range dynamicAssets(path) {
+		addDynamicAsset(r, o, cache, a)
+	}
+
+	// FaviconSVG, unlike Favicon, isn't resolved per request: it's a
+	// single, Add-time-supplied vector image, so serve it the same static
+	// way the non-Resolver path does instead of routing it through
+	// o.Resolver and the asset cache.
+	if o.FaviconSVG != nil {
+		if err := addFaviconSVG(r, o.FaviconSVG, o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dynamicAsset describes one asset served by addDynamic.
+type dynamicAsset struct {
+	name     string
+	mime     string
+	generate func(set *IconSet) ([]byte, error)
+}
+
+// dynamicAssets returns the assets served by addDynamic. path is the
+// router group's base path, needed to resolve the absolute icon URLs
+// embedded in the webmanifest.
+func dynamicAssets(path string) []dynamicAsset {
+	assets := []dynamicAsset{
+		{"apple-touch-icon.png", "image/png", dynamicIcon(180, appleTouchIconOf)},
+		{"favicon.png", "image/png", dynamicIcon(48, faviconOf)},
+		{"favicon-32x32.png", "image/png", dynamicIcon(32, faviconOf)},
+		{"favicon-16x16.png", "image/png", dynamicIcon(16, faviconOf)},
+		{"favicon.ico", "image/x-icon", dynamicFaviconICO},
+		{"mstile-150x150.png", "image/png", dynamicIcon(150, faviconOf)},
+		{"site.webmanifest", "application/manifest+json", dynamicWebmanifest(path)},
+		{"browserconfig.xml", "application/xml", dynamicBrowserConfig},
+	}
+
+	for _, size := range androidChromeIconSizes {
+		size := size
+		assets = append(assets,
+			dynamicAsset{
+				fmt.Sprintf("android-chrome-%dx%d.png", size, size),
+				"image/png",
+				dynamicIcon(size, faviconOf),
+			},
+			dynamicAsset{
+				fmt.Sprintf("android-chrome-maskable-%dx%d.png", size, size),
+				"image/png",
+				dynamicMaskableIcon(size),
+			},
+		)
+	}
+
+	assets = append(assets, dynamicAsset{
+		fmt.Sprintf("android-chrome-monochrome-%dx%d.png", monochromeIconSize, monochromeIconSize),
+		"image/png",
+		dynamicMonochromeIcon(monochromeIconSize),
+	})
+
+	return assets
+}
+
+func dynamicMaskableIcon(size int) func(*IconSet) ([]byte, error) {
+	return func(set *IconSet) ([]byte, error) {
+		bg := set.Options.BackgroundColor
+		if bg == "" {
+			bg = "#ffffff"
+		}
+
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, maskableIcon(set.Favicon, size, parseHexColor(bg)), imaging.PNG); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+}
+
+func dynamicMonochromeIcon(size int) func(*IconSet) ([]byte, error) {
+	return func(set *IconSet) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, monochromeIcon(set.Favicon, size), imaging.PNG); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+}
+
+func addDynamicAsset(r *gin.RouterGroup, o Options, cache *assetCache, a dynamicAsset) {
+	r.GET("/"+a.name, func(gctx *gin.Context) {
+		key := o.ResolverKey(gctx) + "/" + a.name
+
+		data, ok := cache.Get(key)
+		if !ok {
+			set, err := o.Resolver(gctx)
+			if err != nil {
+				_ = gctx.Error(err)
+				gctx.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+
+			data, err = a.generate(set)
+			if err != nil {
+				_ = gctx.Error(err)
+				gctx.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+
+			cache.Add(key, data)
+		}
+
+		gctx.Data(http.StatusOK, a.mime, data)
+	})
+}
+
+func faviconOf(set *IconSet) image.Image { return set.Favicon }
+
+func appleTouchIconOf(set *IconSet) image.Image {
+	if set.AppleTouchIcon != nil {
+		return set.AppleTouchIcon
+	}
+
+	return set.Favicon
+}
+
+func dynamicIcon(size int, imgOf func(*IconSet) image.Image) func(*IconSet) ([]byte, error) {
+	return func(set *IconSet) ([]byte, error) {
+		resized := imaging.Resize(imgOf(set), size, size, imaging.Lanczos)
+
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, resized, imaging.PNG); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+}
+
+func dynamicFaviconICO(set *IconSet) ([]byte, error) {
+	return encodeICO(newIconRendererFromImage(set.Favicon), faviconICOSizes)
+}
+
+func dynamicWebmanifest(path string) func(*IconSet) ([]byte, error) {
+	return func(set *IconSet) ([]byte, error) {
+		return buildWebmanifestJSON(path, set.Options)
+	}
+}
+
+func dynamicBrowserConfig(set *IconSet) ([]byte, error) {
+	return buildBrowserConfigXML(set.Options.TileColor), nil
+}
+
+// assetCache is a fixed-size, thread-safe LRU cache of generated asset
+// bytes, keyed by a caller-supplied string (see Options.ResolverKey).
+type assetCache struct {
+	mu       sync.Mutex
+	size     int
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+type assetCacheEntry struct {
+	key  string
+	data []byte
+}
+
+func newAssetCache(size int) *assetCache {
+	return &assetCache{
+		size:     size,
+		order:    list.New(),
+		elements: make(map[string]*list.Element, size),
+	}
+}
+
+func (c *assetCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.elements[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+
+	return el.Value.(*assetCacheEntry).data, true
+}
+
+func (c *assetCache) Add(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.elements[key]; ok {
+		el.Value.(*assetCacheEntry).data = data
+		c.order.MoveToFront(el)
+		return
+	}
+
+	el := c.order.PushFront(&assetCacheEntry{key: key, data: data})
+	c.elements[key] = el
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.elements, oldest.Value.(*assetCacheEntry).key)
+		}
+	}
+}
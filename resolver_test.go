@@ -0,0 +1,45 @@
+package favicon
+
+import "testing"
+
+func TestAssetCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newAssetCache(2)
+
+	cache.Add("a", []byte("a"))
+	cache.Add("b", []byte("b"))
+
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected \"a\" to still be cached")
+	}
+
+	// "a" is now most-recently-used, so adding "c" should evict "b".
+	cache.Add("c", []byte("c"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected \"b\" to have been evicted")
+	}
+
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("expected \"a\" to still be cached")
+	}
+
+	if _, ok := cache.Get("c"); !ok {
+		t.Error("expected \"c\" to still be cached")
+	}
+}
+
+func TestAssetCacheAddUpdatesExistingEntry(t *testing.T) {
+	cache := newAssetCache(2)
+
+	cache.Add("a", []byte("old"))
+	cache.Add("a", []byte("new"))
+
+	data, ok := cache.Get("a")
+	if !ok {
+		t.Fatal("expected \"a\" to be cached")
+	}
+
+	if string(data) != "new" {
+		t.Errorf("cache.Get(%q) = %q, want %q", "a", data, "new")
+	}
+}
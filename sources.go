@@ -0,0 +1,237 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// Supported [IconSource] formats.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+	FormatICO = "ico"
+)
+
+// IconSource is one input image a favicon/icon can be generated from, see
+// [Options.FaviconSources].
+//
+// Each output size is rendered from the first source whose [MinSize,
+// MaxSize] range covers it, so a vector source can be rasterized fresh at
+// its exact target size instead of being up- or downscaled from a
+// mismatched raster.
+type IconSource struct {
+	// Data is the raw, encoded image.
+	Data []byte
+	// Format is the format Data is encoded in, one of FormatPNG,
+	// FormatSVG, or FormatICO.
+	//
+	// If empty, it is auto-detected by sniffing Data's magic bytes.
+	Format string
+	// MinSize and MaxSize bound, inclusive, the output sizes this source
+	// is used for.
+	//
+	// A zero MaxSize means "no upper bound".
+	MinSize int
+	MaxSize int
+}
+
+// covers reports whether src should be used to render size.
+func (src IconSource) covers(size int) bool {
+	if size < src.MinSize {
+		return false
+	}
+
+	if src.MaxSize != 0 && size > src.MaxSize {
+		return false
+	}
+
+	return true
+}
+
+// iconRenderer renders the best-quality raster available for a given
+// output size, picking among a prioritized list of [IconSource]s.
+type iconRenderer struct {
+	sources []IconSource
+	// decoded is set by newIconRendererFromImage for callers that already
+	// hold a decoded image.Image (e.g. a [Resolver]'s [IconSet]) instead of
+	// encoded [IconSource] bytes. When set, it takes precedence over
+	// sources and is simply resized per request.
+	decoded image.Image
+}
+
+// newIconRenderer builds an iconRenderer from sources, with legacyFavicon
+// (e.g. [Options.Favicon]) appended as a final, full-range, auto-detected
+// source for back-compat.
+func newIconRenderer(sources []IconSource, legacyFavicon []byte) *iconRenderer {
+	all := make([]IconSource, len(sources), len(sources)+1)
+	copy(all, sources)
+
+	if legacyFavicon != nil {
+		all = append(all, IconSource{Data: legacyFavicon, Format: sniffFormat(legacyFavicon)})
+	}
+
+	return &iconRenderer{sources: all}
+}
+
+// newIconRendererFromImage wraps an already-decoded image, such as the
+// [IconSet.Favicon] a [Resolver] returns, so it can be passed anywhere an
+// *iconRenderer is expected (e.g. [encodeICO]) without re-encoding it into
+// an [IconSource] first.
+func newIconRendererFromImage(img image.Image) *iconRenderer {
+	return &iconRenderer{decoded: img}
+}
+
+// At renders the best available source for a size x size output.
+func (ir *iconRenderer) At(size int) (image.Image, error) {
+	if ir.decoded != nil {
+		return imaging.Resize(ir.decoded, size, size, imaging.Lanczos), nil
+	}
+
+	for _, src := range ir.sources {
+		if !src.covers(size) {
+			continue
+		}
+
+		return renderIconSource(src, size)
+	}
+
+	return nil, fmt.Errorf("favicon: no icon source covers size %d", size)
+}
+
+// sniffFormat classifies data by its magic bytes, defaulting to
+// FormatPNG (imaging.Decode, which it delegates to, also handles JPEG,
+// GIF, BMP and TIFF) if it doesn't recognize an SVG or ICO header.
+func sniffFormat(data []byte) string {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<?xml")), bytes.HasPrefix(trimmed, []byte("<svg")):
+		return FormatSVG
+	case bytes.HasPrefix(data, []byte{0x00, 0x00, 0x01, 0x00}):
+		return FormatICO
+	default:
+		return FormatPNG
+	}
+}
+
+// renderIconSource renders src at size x size, rasterizing SVG sources
+// fresh and picking the best-fitting frame out of multi-frame ICO
+// sources, instead of naively resizing a single raster.
+func renderIconSource(src IconSource, size int) (image.Image, error) {
+	switch src.Format {
+	case FormatSVG:
+		return rasterizeSVG(src.Data, size)
+	case FormatICO:
+		frame, err := bestICOFrame(src.Data, size)
+		if err != nil {
+			return nil, err
+		}
+
+		return imaging.Resize(frame, size, size, imaging.Lanczos), nil
+	default:
+		img, err := imaging.Decode(bytes.NewReader(src.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		return imaging.Resize(img, size, size, imaging.Lanczos), nil
+	}
+}
+
+// rasterizeSVG rasterizes the SVG document svg at size x size.
+func rasterizeSVG(svg []byte, size int) (image.Image, error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(svg))
+	if err != nil {
+		return nil, err
+	}
+
+	icon.SetTarget(0, 0, float64(size), float64(size))
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
+	raster := rasterx.NewDasher(size, size, scanner)
+
+	icon.Draw(raster, 1.0)
+
+	return img, nil
+}
+
+// bestICOFrame parses the ICONDIR/ICONDIRENTRY headers of a (potentially
+// multi-frame) ICO file and decodes the smallest embedded frame that is
+// at least size x size, falling back to the largest frame available if
+// none is. Only PNG-encoded frames are supported.
+func bestICOFrame(ico []byte, size int) (image.Image, error) {
+	if len(ico) < icoHeaderSize {
+		return nil, fmt.Errorf("favicon: ico source too short")
+	}
+
+	count := int(binary.LittleEndian.Uint16(ico[4:6]))
+
+	type frame struct {
+		dim    int
+		offset uint32
+		length uint32
+	}
+
+	frames := make([]frame, 0, count)
+
+	for i := 0; i < count; i++ {
+		entryOff := icoHeaderSize + i*icoEntrySize
+		if entryOff+icoEntrySize > len(ico) {
+			return nil, fmt.Errorf("favicon: ico source truncated")
+		}
+
+		entry := ico[entryOff : entryOff+icoEntrySize]
+
+		dim := int(entry[0])
+		if dim == 0 {
+			dim = 256
+		}
+
+		frames = append(frames, frame{
+			dim:    dim,
+			length: binary.LittleEndian.Uint32(entry[8:12]),
+			offset: binary.LittleEndian.Uint32(entry[12:16]),
+		})
+	}
+
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("favicon: ico source has no frames")
+	}
+
+	best := frames[0]
+
+	for _, f := range frames {
+		switch {
+		case f.dim >= size && (best.dim < size || f.dim < best.dim):
+			best = f
+		case best.dim < size && f.dim > best.dim:
+			best = f
+		}
+	}
+
+	if int(best.offset)+int(best.length) > len(ico) {
+		return nil, fmt.Errorf("favicon: ico frame out of bounds")
+	}
+
+	data := ico[best.offset : best.offset+best.length]
+
+	return decodeICOFramePNG(data)
+}
+
+// decodeICOFramePNG decodes a PNG-encoded ICO frame. BMP-encoded frames
+// (the legacy, pre-Vista ICO format) aren't supported.
+func decodeICOFramePNG(data []byte) (image.Image, error) {
+	img, err := imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("favicon: unsupported (likely BMP-encoded) ico frame: %w", err)
+	}
+
+	return img, nil
+}
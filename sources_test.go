@@ -0,0 +1,125 @@
+package favicon
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestSniffFormat(t *testing.T) {
+	png := func() []byte {
+		var buf bytes.Buffer
+		_ = imaging.Encode(&buf, imaging.New(2, 2, color.NRGBA{A: 0xff}), imaging.PNG)
+		return buf.Bytes()
+	}()
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"png magic bytes", png, FormatPNG},
+		{"svg xml prolog", []byte(`<?xml version="1.0"?><svg/>`), FormatSVG},
+		{"svg tag, no prolog", []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), FormatSVG},
+		{"svg with leading whitespace", []byte("\n\t  <svg/>"), FormatSVG},
+		{"ico magic bytes", []byte{0x00, 0x00, 0x01, 0x00, 0xaa}, FormatICO},
+		{"unrecognized falls back to png", []byte("not an image"), FormatPNG},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffFormat(tt.data); got != tt.want {
+				t.Errorf("sniffFormat(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIconSourceCovers(t *testing.T) {
+	tests := []struct {
+		name string
+		src  IconSource
+		size int
+		want bool
+	}{
+		{"within bounded range", IconSource{MinSize: 16, MaxSize: 32}, 24, true},
+		{"below min", IconSource{MinSize: 16, MaxSize: 32}, 8, false},
+		{"above max", IconSource{MinSize: 16, MaxSize: 32}, 64, false},
+		{"zero max means unbounded above", IconSource{MinSize: 16, MaxSize: 0}, 512, true},
+		{"at min boundary", IconSource{MinSize: 16, MaxSize: 32}, 16, true},
+		{"at max boundary", IconSource{MinSize: 16, MaxSize: 32}, 32, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.src.covers(tt.size); got != tt.want {
+				t.Errorf("IconSource%+v.covers(%d) = %v, want %v", tt.src, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIconRendererAtPicksFirstCoveringSource(t *testing.T) {
+	var small, large bytes.Buffer
+	_ = imaging.Encode(&small, imaging.New(16, 16, color.NRGBA{R: 0xff, A: 0xff}), imaging.PNG)
+	_ = imaging.Encode(&large, imaging.New(256, 256, color.NRGBA{B: 0xff, A: 0xff}), imaging.PNG)
+
+	renderer := newIconRenderer([]IconSource{
+		{Data: small.Bytes(), Format: FormatPNG, MinSize: 0, MaxSize: 32},
+		{Data: large.Bytes(), Format: FormatPNG, MinSize: 33},
+	}, nil)
+
+	img, err := renderer.At(16)
+	if err != nil {
+		t.Fatalf("At(16): %v", err)
+	}
+
+	if bounds := img.Bounds(); bounds.Dx() != 16 {
+		t.Errorf("At(16) size = %d, want 16", bounds.Dx())
+	}
+
+	if r, _, b, _ := img.At(0, 0).RGBA(); r == 0 || b != 0 {
+		t.Errorf("At(16) pixel r=%d b=%d, want red from the small source", r, b)
+	}
+
+	img, err = renderer.At(180)
+	if err != nil {
+		t.Fatalf("At(180): %v", err)
+	}
+
+	if bounds := img.Bounds(); bounds.Dx() != 180 {
+		t.Errorf("At(180) size = %d, want 180", bounds.Dx())
+	}
+
+	if r, _, b, _ := img.At(90, 90).RGBA(); r != 0 || b == 0 {
+		t.Errorf("At(180) pixel r=%d b=%d, want blue from the large source", r, b)
+	}
+}
+
+func TestIconRendererAtNoCoveringSource(t *testing.T) {
+	renderer := newIconRenderer([]IconSource{
+		{Data: nil, Format: FormatPNG, MinSize: 0, MaxSize: 16},
+	}, nil)
+
+	if _, err := renderer.At(64); err == nil {
+		t.Error("At(64) returned no error despite no source covering that size")
+	}
+}
+
+func TestIconRendererAtFallsBackToLegacyFavicon(t *testing.T) {
+	var buf bytes.Buffer
+	_ = imaging.Encode(&buf, imaging.New(32, 32, color.NRGBA{G: 0xff, A: 0xff}), imaging.PNG)
+
+	renderer := newIconRenderer(nil, buf.Bytes())
+
+	img, err := renderer.At(48)
+	if err != nil {
+		t.Fatalf("At(48): %v", err)
+	}
+
+	if bounds := img.Bounds(); bounds.Dx() != 48 {
+		t.Errorf("At(48) size = %d, want 48", bounds.Dx())
+	}
+}
@@ -0,0 +1,187 @@
+package favicon
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaxAge is the Cache-Control max-age used when [Options.MaxAge] is
+// zero.
+const defaultMaxAge = 7 * 24 * time.Hour
+
+// Encoder produces an alternative, compressed representation of an asset,
+// identified by the Content-Encoding token it produces, e.g. "br" for
+// brotli. Register one via [Options.ExtraEncodings] to precompute a
+// brotli variant using github.com/andybalholm/brotli, without making it a
+// hard dependency of this package.
+type Encoder interface {
+	// Encoding is the Content-Encoding token this Encoder produces.
+	Encoding() string
+	// Encode returns the compressed representation of data.
+	Encode(data []byte) ([]byte, error)
+}
+
+// encodedVariant is a precomputed, compressed representation of an asset.
+type encodedVariant struct {
+	encoding string
+	data     []byte
+}
+
+// serveStatic registers a GET handler for name on r that serves body as
+// mime, with an ETag derived from body's content and a long-lived
+// Cache-Control header. It honors If-None-Match with a 304, and, unless
+// [Options.DisableCompression] is set, transparently serves a
+// precompressed gzip (and any [Options.ExtraEncodings]) variant chosen
+// from the request's Accept-Encoding header.
+func serveStatic(r *gin.RouterGroup, name, mime string, body []byte, o Options) error {
+	handler, err := buildStaticHandler(mime, body, o)
+	if err != nil {
+		return err
+	}
+
+	r.GET("/"+strings.TrimPrefix(name, "/"), handler)
+
+	return nil
+}
+
+// buildStaticHandler builds the [gin.HandlerFunc] serveStatic registers,
+// without tying it to a route. [Middleware] uses this directly to serve
+// assets outside of a [gin.RouterGroup].
+func buildStaticHandler(mime string, body []byte, o Options) (gin.HandlerFunc, error) {
+	etag := `"` + sha256Hex(body) + `"`
+
+	maxAge := o.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultMaxAge
+	}
+
+	cacheControl := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds())) + ", immutable"
+
+	var variants []encodedVariant
+
+	if !o.DisableCompression {
+		for _, enc := range o.ExtraEncodings {
+			data, err := enc.Encode(body)
+			if err != nil {
+				return nil, err
+			}
+
+			variants = append(variants, encodedVariant{encoding: enc.Encoding(), data: data})
+		}
+
+		gz, err := gzipEncode(body)
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, encodedVariant{encoding: "gzip", data: gz})
+	}
+
+	return func(gctx *gin.Context) {
+		gctx.Header("ETag", etag)
+		gctx.Header("Cache-Control", cacheControl)
+
+		if len(variants) > 0 {
+			// Set unconditionally, not only when a variant is picked below:
+			// a shared cache that stores an identity response for this
+			// request without Vary would replay it to later requests that
+			// do send an Accept-Encoding it could have satisfied (and vice
+			// versa for a cached compressed response).
+			gctx.Header("Vary", "Accept-Encoding")
+		}
+
+		if ifNoneMatch := gctx.GetHeader("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+			gctx.Status(http.StatusNotModified)
+			return
+		}
+
+		data := body
+
+		if variant, ok := pickEncoding(gctx.GetHeader("Accept-Encoding"), variants); ok {
+			gctx.Header("Content-Encoding", variant.encoding)
+			data = variant.data
+		}
+
+		gctx.Data(http.StatusOK, mime, data)
+	}, nil
+}
+
+// sha256Hex returns the hex-encoded SHA-256 digest of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// gzipEncode returns the gzip-compressed representation of data.
+func gzipEncode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// etagMatches reports whether etag appears in the comma-separated
+// If-None-Match header value ifNoneMatch, or whether ifNoneMatch is "*".
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pickEncoding returns the first of variants whose encoding is accepted by
+// the Accept-Encoding header value acceptEncoding, preferring variants in
+// the order they were given (caller-supplied [Options.ExtraEncodings]
+// before the built-in gzip fallback).
+func pickEncoding(acceptEncoding string, variants []encodedVariant) (encodedVariant, bool) {
+	if acceptEncoding == "" || len(variants) == 0 {
+		return encodedVariant{}, false
+	}
+
+	accepted := make(map[string]bool)
+
+	for _, tok := range strings.Split(acceptEncoding, ",") {
+		parts := strings.SplitN(tok, ";", 2)
+		encoding := strings.TrimSpace(parts[0])
+
+		// A q=0 parameter explicitly forbids this encoding, e.g.
+		// "gzip;q=0" means "never send me gzip".
+		if len(parts) == 2 && strings.TrimSpace(strings.ReplaceAll(parts[1], " ", "")) == "q=0" {
+			accepted[encoding] = false
+			continue
+		}
+
+		accepted[encoding] = true
+	}
+
+	for _, variant := range variants {
+		if accepted[variant.encoding] {
+			return variant, true
+		}
+	}
+
+	return encodedVariant{}, false
+}
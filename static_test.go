@@ -0,0 +1,110 @@
+package favicon
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		etag        string
+		want        bool
+	}{
+		{"exact match", `"abc"`, `"abc"`, true},
+		{"wildcard", "*", `"abc"`, true},
+		{"one of several", `"xyz", "abc"`, `"abc"`, true},
+		{"no match", `"xyz"`, `"abc"`, false},
+		{"empty header", "", `"abc"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatches(tt.ifNoneMatch, tt.etag); got != tt.want {
+				t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.ifNoneMatch, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickEncoding(t *testing.T) {
+	variants := []encodedVariant{
+		{encoding: "br", data: []byte("br-data")},
+		{encoding: "gzip", data: []byte("gzip-data")},
+	}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantEncoding   string
+		wantOK         bool
+	}{
+		{"prefers first accepted variant", "gzip, br", "br", true},
+		{"falls back to later variant", "gzip", "gzip", true},
+		{"ignores quality values", "br;q=0.5", "br", true},
+		{"q=0 forbids an encoding", "gzip;q=0, br", "br", true},
+		{"q=0 on the only accepted encoding", "gzip;q=0", "", false},
+		{"no variants accepted", "deflate", "", false},
+		{"empty header", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pickEncoding(tt.acceptEncoding, variants)
+			if ok != tt.wantOK {
+				t.Fatalf("pickEncoding(%q) ok = %v, want %v", tt.acceptEncoding, ok, tt.wantOK)
+			}
+
+			if ok && got.encoding != tt.wantEncoding {
+				t.Errorf("pickEncoding(%q) encoding = %q, want %q", tt.acceptEncoding, got.encoding, tt.wantEncoding)
+			}
+		})
+	}
+
+	if _, ok := pickEncoding("gzip", nil); ok {
+		t.Error("pickEncoding with no variants should never match")
+	}
+}
+
+func TestSha256HexIsDeterministic(t *testing.T) {
+	a := sha256Hex([]byte("hello"))
+	b := sha256Hex([]byte("hello"))
+
+	if a != b {
+		t.Errorf("sha256Hex is not deterministic: %q != %q", a, b)
+	}
+
+	if c := sha256Hex([]byte("world")); c == a {
+		t.Error("sha256Hex returned the same digest for different input")
+	}
+}
+
+func TestGzipEncodeRoundTrips(t *testing.T) {
+	data := []byte("some favicon bytes to compress")
+
+	gz, err := gzipEncode(data)
+	if err != nil {
+		t.Fatalf("gzipEncode: %v", err)
+	}
+
+	if len(gz) == 0 {
+		t.Fatal("gzipEncode returned no data")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("read gzip data: %v", err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Errorf("gzipEncode round trip = %q, want %q", got, data)
+	}
+}